@@ -37,11 +37,43 @@ var (
 	rpcKeepalive    = flag.Duration("rpc_keepalive", 10*time.Second, "gRPC keepalive")
 )
 
+// PosixClient is the interface DUT uses to issue POSIX calls. It is
+// satisfied by the generated posix_server gRPC client, and by any fake
+// (e.g. fakeposix) that wants to stand in for a real DUT in hermetic tests.
+type PosixClient = pb.PosixClient
+
 // DUT communicates with the DUT to force it to make POSIX calls.
 type DUT struct {
 	t           *testing.T
 	conn        *grpc.ClientConn
 	posixServer PosixClient
+	ctx         context.Context
+	cancel      context.CancelFunc
+}
+
+// NewDUTContext creates a DUT whose blocking wrappers (Accept, Recv,
+// Connect, etc.) derive their per-call context from ctx instead of
+// context.Background(). This lets a test cancel every in-flight call at
+// once, e.g. when a parallel expectation on the sniffer side fails, rather
+// than waiting out rpcTimeout on each one. Call (*DUT).Shutdown to cancel
+// ctx and release it.
+func NewDUTContext(ctx context.Context, t *testing.T) DUT {
+	ctx, cancel := context.WithCancel(ctx)
+	return DUT{
+		t:      t,
+		ctx:    ctx,
+		cancel: cancel,
+	}
+}
+
+// NewDUTWithClient creates a DUT that issues POSIX calls through posixServer
+// directly, skipping the gRPC dial NewDUT otherwise performs. This lets
+// tests substitute an in-process fake, such as fakeposix, for the real
+// posix_server.
+func NewDUTWithClient(t *testing.T, posixServer PosixClient) DUT {
+	dut := NewDUTContext(context.Background(), t)
+	dut.posixServer = posixServer
+	return dut
 }
 
 // NewDUT creates a new connection with the DUT over gRPC.
@@ -52,17 +84,28 @@ func NewDUT(t *testing.T) DUT {
 	if err != nil {
 		t.Fatalf("failed to grpc.Dial(%s): %s", posixServerAddress, err)
 	}
-	posixServer := NewPosixClient(conn)
-	return DUT{
-		t:           t,
-		conn:        conn,
-		posixServer: posixServer,
-	}
+	dut := NewDUTWithClient(t, pb.NewPosixClient(conn))
+	dut.conn = conn
+	return dut
+}
+
+// Shutdown cancels the context every blocking wrapper derives its per-call
+// context from, unblocking any in-flight RPC immediately instead of making
+// it wait out rpcTimeout.
+func (dut *DUT) Shutdown() {
+	dut.cancel()
 }
 
-// TearDown closes the underlying connection.
+// TearDown closes the underlying connection, if one was opened by NewDUT,
+// and cancels the DUT's context so callers that never call Shutdown don't
+// leak it.
 func (dut *DUT) TearDown() {
-	dut.conn.Close()
+	if dut.cancel != nil {
+		dut.cancel()
+	}
+	if dut.conn != nil {
+		dut.conn.Close()
+	}
 }
 
 func (dut *DUT) sockaddrToProto(sa unix.Sockaddr) *pb.Sockaddr {
@@ -90,6 +133,28 @@ func (dut *DUT) sockaddrToProto(sa unix.Sockaddr) *pb.Sockaddr {
 				},
 			},
 		}
+	case *unix.SockaddrLinklayer:
+		return &pb.Sockaddr{
+			Sockaddr: &pb.Sockaddr_Ll{
+				Ll: &pb.SockaddrLl{
+					Family:   unix.AF_PACKET,
+					Protocol: uint32(s.Protocol),
+					Ifindex:  int32(s.Ifindex),
+					Hatype:   uint32(s.Hatype),
+					Pkttype:  uint32(s.Pkttype),
+					Addr:     s.Addr[:s.Halen],
+				},
+			},
+		}
+	case *unix.SockaddrUnix:
+		return &pb.Sockaddr{
+			Sockaddr: &pb.Sockaddr_Un{
+				Un: &pb.SockaddrUn{
+					Family: unix.AF_UNIX,
+					Path:   s.Name,
+				},
+			},
+		}
 	}
 	dut.t.Fatalf("can't parse Sockaddr: %+v", sa)
 	return nil
@@ -110,6 +175,20 @@ func (dut *DUT) protoToSockaddr(sa *pb.Sockaddr) unix.Sockaddr {
 			ZoneId: s.In6.GetScopeId(),
 		}
 		copy(ret.Addr[:], s.In6.GetAddr())
+	case *pb.Sockaddr_Ll:
+		ret := unix.SockaddrLinklayer{
+			Protocol: uint16(s.Ll.GetProtocol()),
+			Ifindex:  int(s.Ll.GetIfindex()),
+			Hatype:   uint16(s.Ll.GetHatype()),
+			Pkttype:  uint8(s.Ll.GetPkttype()),
+			Halen:    uint8(len(s.Ll.GetAddr())),
+		}
+		copy(ret.Addr[:], s.Ll.GetAddr())
+		return &ret
+	case *pb.Sockaddr_Un:
+		return &unix.SockaddrUnix{
+			Name: s.Un.GetPath(),
+		}
 	}
 	dut.t.Fatalf("can't parse Sockaddr: %+v", sa)
 	return nil
@@ -147,6 +226,15 @@ func (dut *DUT) CreateBoundSocket(typ, proto int32, addr net.IP) (int32, uint16)
 	return fd, uint16(port)
 }
 
+// CreateBoundSocketNonBlocking is CreateBoundSocket with SOCK_NONBLOCK and
+// SOCK_CLOEXEC set on typ, for tests that need to drive an EAGAIN-based
+// state machine rather than have the RPC block on recv/accept until
+// rpcTimeout.
+func (dut *DUT) CreateBoundSocketNonBlocking(typ, proto int32, addr net.IP) (int32, uint16) {
+	dut.t.Helper()
+	return dut.CreateBoundSocket(typ|unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC, proto, addr)
+}
+
 // CreateListener makes a new TCP connection. If it fails, the test ends.
 func (dut *DUT) CreateListener(typ, proto, backlog int32) (int32, uint16) {
 	fd, remotePort := dut.CreateBoundSocket(typ, proto, net.ParseIP(*remoteIPv4))
@@ -162,7 +250,7 @@ func (dut *DUT) CreateListener(typ, proto, backlog int32) (int32, uint16) {
 // AcceptWithErrno.
 func (dut *DUT) Accept(sockfd int32) (int32, unix.Sockaddr) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	fd, sa, err := dut.AcceptWithErrno(ctx, sockfd)
 	if fd < 0 {
@@ -184,12 +272,27 @@ func (dut *DUT) AcceptWithErrno(ctx context.Context, sockfd int32) (int32, unix.
 	return resp.GetFd(), dut.protoToSockaddr(resp.GetAddr()), syscall.Errno(resp.GetErrno_())
 }
 
+// AcceptContext calls accept on the DUT using ctx directly, rather than a
+// context derived from the DUT's context with rpcTimeout, and causes a fatal test
+// failure if it doesn't succeed. This lets a caller thread its own
+// deadline, such as t.Deadline() or an errgroup context, straight through
+// to the gRPC call, mirroring the Dial/DialContext split in Go's net
+// package.
+func (dut *DUT) AcceptContext(ctx context.Context, sockfd int32) (int32, unix.Sockaddr) {
+	dut.t.Helper()
+	fd, sa, err := dut.AcceptWithErrno(ctx, sockfd)
+	if fd < 0 {
+		dut.t.Fatalf("failed to accept: %s", err)
+	}
+	return fd, sa
+}
+
 // Bind calls bind on the DUT and causes a fatal test failure if it doesn't
 // succeed. If more control over the timeout or error handling is
 // needed, use BindWithErrno.
 func (dut *DUT) Bind(fd int32, sa unix.Sockaddr) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.BindWithErrno(ctx, fd, sa)
 	if ret != 0 {
@@ -216,7 +319,7 @@ func (dut *DUT) BindWithErrno(ctx context.Context, fd int32, sa unix.Sockaddr) (
 // CloseWithErrno.
 func (dut *DUT) Close(fd int32) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.CloseWithErrno(ctx, fd)
 	if ret != 0 {
@@ -242,7 +345,7 @@ func (dut *DUT) CloseWithErrno(ctx context.Context, fd int32) (int32, error) {
 // needed, use ConnectWithErrno.
 func (dut *DUT) Connect(fd int32, sa unix.Sockaddr) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.ConnectWithErrno(ctx, fd, sa)
 	if ret != 0 {
@@ -264,12 +367,127 @@ func (dut *DUT) ConnectWithErrno(ctx context.Context, fd int32, sa unix.Sockaddr
 	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
 }
 
+// ConnectContext calls connect on the DUT using ctx directly, rather than a
+// context derived from the DUT's context with rpcTimeout, and causes a fatal test
+// failure if it doesn't succeed. See AcceptContext for why this is useful.
+func (dut *DUT) ConnectContext(ctx context.Context, fd int32, sa unix.Sockaddr) {
+	dut.t.Helper()
+	ret, err := dut.ConnectWithErrno(ctx, fd, sa)
+	if ret != 0 {
+		dut.t.Fatalf("failed to connect socket: %s", err)
+	}
+}
+
+// EpollCreate calls epoll_create1 on the DUT and causes a fatal test failure
+// if it doesn't succeed. If more control over the timeout or error handling
+// is needed, use EpollCreateWithErrno.
+func (dut *DUT) EpollCreate(flags int32) int32 {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	fd, err := dut.EpollCreateWithErrno(ctx, flags)
+	if fd < 0 {
+		dut.t.Fatalf("failed to epoll_create1: %s", err)
+	}
+	return fd
+}
+
+// EpollCreateWithErrno calls epoll_create1 on the DUT.
+func (dut *DUT) EpollCreateWithErrno(ctx context.Context, flags int32) (int32, error) {
+	dut.t.Helper()
+	req := pb.EpollCreateRequest{
+		Flags: flags,
+	}
+	resp, err := dut.posixServer.EpollCreate(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call EpollCreate: %s", err)
+	}
+	return resp.GetFd(), syscall.Errno(resp.GetErrno_())
+}
+
+// EpollEvent mirrors the Linux struct epoll_event used by EpollCtl and
+// EpollWait.
+type EpollEvent struct {
+	Events uint32
+	FD     int32
+}
+
+func epollEventToProto(e EpollEvent) *pb.EpollEvent {
+	return &pb.EpollEvent{Events: e.Events, Fd: e.FD}
+}
+
+func epollEventFromProto(e *pb.EpollEvent) EpollEvent {
+	return EpollEvent{Events: e.GetEvents(), FD: e.GetFd()}
+}
+
+// EpollCtl calls epoll_ctl on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use EpollCtlWithErrno.
+func (dut *DUT) EpollCtl(epfd, op, fd int32, event EpollEvent) {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	ret, err := dut.EpollCtlWithErrno(ctx, epfd, op, fd, event)
+	if ret != 0 {
+		dut.t.Fatalf("failed to epoll_ctl: %s", err)
+	}
+}
+
+// EpollCtlWithErrno calls epoll_ctl on the DUT.
+func (dut *DUT) EpollCtlWithErrno(ctx context.Context, epfd, op, fd int32, event EpollEvent) (int32, error) {
+	dut.t.Helper()
+	req := pb.EpollCtlRequest{
+		Epfd:  epfd,
+		Op:    op,
+		Fd:    fd,
+		Event: epollEventToProto(event),
+	}
+	resp, err := dut.posixServer.EpollCtl(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call EpollCtl: %s", err)
+	}
+	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
+}
+
+// EpollWait calls epoll_wait on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use EpollWaitWithErrno.
+func (dut *DUT) EpollWait(epfd int32, maxEvents int32, timeout time.Duration) []EpollEvent {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout+timeout)
+	defer cancel()
+	ret, events, err := dut.EpollWaitWithErrno(ctx, epfd, maxEvents, timeout)
+	if ret < 0 {
+		dut.t.Fatalf("failed to epoll_wait: %s", err)
+	}
+	return events
+}
+
+// EpollWaitWithErrno calls epoll_wait on the DUT.
+func (dut *DUT) EpollWaitWithErrno(ctx context.Context, epfd int32, maxEvents int32, timeout time.Duration) (int32, []EpollEvent, error) {
+	dut.t.Helper()
+	req := pb.EpollWaitRequest{
+		Epfd:          epfd,
+		Maxevents:     maxEvents,
+		TimeoutMillis: int32(timeout.Milliseconds()),
+	}
+	resp, err := dut.posixServer.EpollWait(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call EpollWait: %s", err)
+	}
+	events := make([]EpollEvent, 0, len(resp.GetEvents()))
+	for _, e := range resp.GetEvents() {
+		events = append(events, epollEventFromProto(e))
+	}
+	return resp.GetRet(), events, syscall.Errno(resp.GetErrno_())
+}
+
 // GetSockName calls getsockname on the DUT and causes a fatal test failure if
 // it doesn't succeed. If more control over the timeout or error handling is
 // needed, use GetSockNameWithErrno.
 func (dut *DUT) GetSockName(sockfd int32) unix.Sockaddr {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, sa, err := dut.GetSockNameWithErrno(ctx, sockfd)
 	if ret != 0 {
@@ -298,7 +516,7 @@ func (dut *DUT) GetSockNameWithErrno(ctx context.Context, sockfd int32) (int32,
 // more specific GetSockOptXxx function.
 func (dut *DUT) GetSockOpt(sockfd, level, optname, optlen int32) []byte {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, optval, err := dut.GetSockOptWithErrno(ctx, sockfd, level, optname, optlen)
 	if ret != 0 {
@@ -330,7 +548,7 @@ func (dut *DUT) GetSockOptWithErrno(ctx context.Context, sockfd, level, optname,
 // is needed, use GetSockOptIntWithErrno.
 func (dut *DUT) GetSockOptInt(sockfd, level, optname int32) int32 {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, intval, err := dut.GetSockOptIntWithErrno(ctx, sockfd, level, optname)
 	if ret != 0 {
@@ -359,7 +577,7 @@ func (dut *DUT) GetSockOptIntWithErrno(ctx context.Context, sockfd, level, optna
 // needed, use GetSockOptTimevalWithErrno.
 func (dut *DUT) GetSockOptTimeval(sockfd, level, optname int32) unix.Timeval {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, timeval, err := dut.GetSockOptTimevalWithErrno(ctx, sockfd, level, optname)
 	if ret != 0 {
@@ -392,7 +610,7 @@ func (dut *DUT) GetSockOptTimevalWithErrno(ctx context.Context, sockfd, level, o
 // ListenWithErrno.
 func (dut *DUT) Listen(sockfd, backlog int32) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.ListenWithErrno(ctx, sockfd, backlog)
 	if ret != 0 {
@@ -414,12 +632,103 @@ func (dut *DUT) ListenWithErrno(ctx context.Context, sockfd, backlog int32) (int
 	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
 }
 
+// PollFD mirrors the Linux struct pollfd used by Poll and Select.
+type PollFD struct {
+	FD      int32
+	Events  int32
+	REvents int32
+}
+
+func pollFDToProto(p PollFD) *pb.PollFD {
+	return &pb.PollFD{Fd: p.FD, Events: p.Events, Revents: p.REvents}
+}
+
+func pollFDFromProto(p *pb.PollFD) PollFD {
+	return PollFD{FD: p.GetFd(), Events: p.GetEvents(), REvents: p.GetRevents()}
+}
+
+// Poll calls poll on the DUT and causes a fatal test failure if it doesn't
+// succeed. If more control over the timeout or error handling is needed, use
+// PollWithErrno.
+func (dut *DUT) Poll(pfds []PollFD, timeout time.Duration) []PollFD {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout+timeout)
+	defer cancel()
+	ret, got, err := dut.PollWithErrno(ctx, pfds, timeout)
+	if ret < 0 {
+		dut.t.Fatalf("failed to poll: %s", err)
+	}
+	return got
+}
+
+// PollWithErrno calls poll on the DUT.
+func (dut *DUT) PollWithErrno(ctx context.Context, pfds []PollFD, timeout time.Duration) (int32, []PollFD, error) {
+	dut.t.Helper()
+	protoFDs := make([]*pb.PollFD, 0, len(pfds))
+	for _, p := range pfds {
+		protoFDs = append(protoFDs, pollFDToProto(p))
+	}
+	req := pb.PollRequest{
+		Pfds:          protoFDs,
+		TimeoutMillis: int32(timeout.Milliseconds()),
+	}
+	resp, err := dut.posixServer.Poll(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call Poll: %s", err)
+	}
+	got := make([]PollFD, 0, len(resp.GetPfds()))
+	for _, p := range resp.GetPfds() {
+		got = append(got, pollFDFromProto(p))
+	}
+	return resp.GetRet(), got, syscall.Errno(resp.GetErrno_())
+}
+
+// Select calls select on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use SelectWithErrno. A nil timeout passes select a NULL timeout on
+// the DUT, but the RPC itself still expires after rpcTimeout, failing the
+// test.
+func (dut *DUT) Select(readFDs, writeFDs, exceptFDs []int32, timeout *time.Duration) (rFDs, wFDs, eFDs []int32) {
+	dut.t.Helper()
+	ctxTimeout := *rpcTimeout
+	if timeout != nil {
+		ctxTimeout += *timeout
+	}
+	ctx, cancel := context.WithTimeout(dut.ctx, ctxTimeout)
+	defer cancel()
+	ret, rFDs, wFDs, eFDs, err := dut.SelectWithErrno(ctx, readFDs, writeFDs, exceptFDs, timeout)
+	if ret < 0 {
+		dut.t.Fatalf("failed to select: %s", err)
+	}
+	return rFDs, wFDs, eFDs
+}
+
+// SelectWithErrno calls select on the DUT. A nil timeout blocks
+// indefinitely, matching select's NULL timeout semantics.
+func (dut *DUT) SelectWithErrno(ctx context.Context, readFDs, writeFDs, exceptFDs []int32, timeout *time.Duration) (int32, []int32, []int32, []int32, error) {
+	dut.t.Helper()
+	req := pb.SelectRequest{
+		Readfds:   readFDs,
+		Writefds:  writeFDs,
+		Exceptfds: exceptFDs,
+	}
+	if timeout != nil {
+		req.HaveTimeout = true
+		req.TimeoutMillis = int32(timeout.Milliseconds())
+	}
+	resp, err := dut.posixServer.Select(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call Select: %s", err)
+	}
+	return resp.GetRet(), resp.GetReadfds(), resp.GetWritefds(), resp.GetExceptfds(), syscall.Errno(resp.GetErrno_())
+}
+
 // Send calls send on the DUT and causes a fatal test failure if it doesn't
 // succeed. If more control over the timeout or error handling is needed, use
 // SendWithErrno.
 func (dut *DUT) Send(sockfd int32, buf []byte, flags int32) int32 {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.SendWithErrno(ctx, sockfd, buf, flags)
 	if ret == -1 {
@@ -443,12 +752,131 @@ func (dut *DUT) SendWithErrno(ctx context.Context, sockfd int32, buf []byte, fla
 	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
 }
 
+// SendStream sends each buffer received from bufs to the DUT over a single
+// flow-controlled gRPC stream bound to sockfd, instead of paying a unary
+// RPC round-trip per Send. This keeps bulk-transfer tests (congestion
+// control, window scaling, SACK) from measuring RPC latency instead of the
+// behavior under test. It causes a fatal test failure if the stream or any
+// send on it fails, and returns the cumulative bytes sent once bufs is
+// drained and closed.
+func (dut *DUT) SendStream(ctx context.Context, sockfd int32, bufs <-chan []byte, flags int32) int32 {
+	dut.t.Helper()
+	stream, err := dut.posixServer.SendStream(ctx)
+	if err != nil {
+		dut.t.Fatalf("failed to open SendStream: %s", err)
+	}
+	for buf := range bufs {
+		if err := stream.Send(&pb.SendStreamRequest{Sockfd: sockfd, Buf: buf, Flags: flags}); err != nil {
+			dut.t.Fatalf("failed to send on SendStream: %s", err)
+		}
+	}
+	resp, err := stream.CloseAndRecv()
+	if err != nil {
+		dut.t.Fatalf("failed to close SendStream: %s", err)
+	}
+	if resp.GetRet() < 0 {
+		dut.t.Fatalf("SendStream failed: %s", syscall.Errno(resp.GetErrno_()))
+	}
+	return resp.GetRet()
+}
+
+// SendBatch issues a single sendmmsg(2) on the DUT with one message per
+// element of bufs, removing the per-syscall RPC latency that bufs many
+// SendWithErrno calls would otherwise pay. It causes a fatal test failure
+// only if the RPC itself fails; the per-message rets and errnos that
+// sendmmsg reported on the DUT are returned for the caller to check.
+func (dut *DUT) SendBatch(sockfd int32, bufs [][]byte, flags int32) ([]int32, []error) {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	req := pb.SendBatchRequest{
+		Sockfd: sockfd,
+		Bufs:   bufs,
+		Flags:  flags,
+	}
+	resp, err := dut.posixServer.SendBatch(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call SendBatch: %s", err)
+	}
+	errs := make([]error, 0, len(resp.GetErrnos()))
+	for _, e := range resp.GetErrnos() {
+		errs = append(errs, syscall.Errno(e))
+	}
+	return resp.GetRets(), errs
+}
+
+// ControlMessage is a single cmsghdr-style ancillary message exchanged by
+// SendMsg and RecvMsg, e.g. IP_PKTINFO, IPV6_RECVPKTINFO, SO_TIMESTAMPNS, or
+// SCM_RIGHTS.
+type ControlMessage struct {
+	Level int32
+	Type  int32
+	Data  []byte
+}
+
+func controlMessagesToProto(cmsgs []ControlMessage) []*pb.ControlMessage {
+	protoCmsgs := make([]*pb.ControlMessage, 0, len(cmsgs))
+	for _, c := range cmsgs {
+		protoCmsgs = append(protoCmsgs, &pb.ControlMessage{
+			Level: c.Level,
+			Type:  c.Type,
+			Data:  c.Data,
+		})
+	}
+	return protoCmsgs
+}
+
+func controlMessagesFromProto(cmsgs []*pb.ControlMessage) []ControlMessage {
+	ret := make([]ControlMessage, 0, len(cmsgs))
+	for _, c := range cmsgs {
+		ret = append(ret, ControlMessage{
+			Level: c.GetLevel(),
+			Type:  c.GetType(),
+			Data:  c.GetData(),
+		})
+	}
+	return ret
+}
+
+// SendMsg calls sendmsg on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use SendMsgWithErrno.
+func (dut *DUT) SendMsg(sockfd int32, iov [][]byte, control []ControlMessage, flags int32, destAddr unix.Sockaddr) int32 {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	ret, err := dut.SendMsgWithErrno(ctx, sockfd, iov, control, flags, destAddr)
+	if ret == -1 {
+		dut.t.Fatalf("failed to sendmsg: %s", err)
+	}
+	return ret
+}
+
+// SendMsgWithErrno calls sendmsg on the DUT.
+func (dut *DUT) SendMsgWithErrno(ctx context.Context, sockfd int32, iov [][]byte, control []ControlMessage, flags int32, destAddr unix.Sockaddr) (int32, error) {
+	dut.t.Helper()
+	req := pb.SendMsgRequest{
+		Sockfd:  sockfd,
+		Iov:     iov,
+		Control: controlMessagesToProto(control),
+		Flags:   flags,
+	}
+	if destAddr != nil {
+		req.DestAddr = dut.sockaddrToProto(destAddr)
+	}
+	resp, err := dut.posixServer.SendMsg(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call SendMsg: %s", err)
+	}
+	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
+}
+
 // SendTo calls sendto on the DUT and causes a fatal test failure if it doesn't
 // succeed. If more control over the timeout or error handling is needed, use
 // SendToWithErrno.
 func (dut *DUT) SendTo(sockfd int32, buf []byte, flags int32, destAddr unix.Sockaddr) int32 {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.SendToWithErrno(ctx, sockfd, buf, flags, destAddr)
 	if ret == -1 {
@@ -480,7 +908,7 @@ func (dut *DUT) SendToWithErrno(ctx context.Context, sockfd int32, buf []byte, f
 // more specific SetSockOptXxx function.
 func (dut *DUT) SetSockOpt(sockfd, level, optname int32, optval []byte) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.SetSockOptWithErrno(ctx, sockfd, level, optname, optval)
 	if ret != 0 {
@@ -511,7 +939,7 @@ func (dut *DUT) SetSockOptWithErrno(ctx context.Context, sockfd, level, optname
 // is needed, use SetSockOptIntWithErrno.
 func (dut *DUT) SetSockOptInt(sockfd, level, optname, optval int32) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.SetSockOptIntWithErrno(ctx, sockfd, level, optname, optval)
 	if ret != 0 {
@@ -540,7 +968,7 @@ func (dut *DUT) SetSockOptIntWithErrno(ctx context.Context, sockfd, level, optna
 // needed, use SetSockOptTimevalWithErrno.
 func (dut *DUT) SetSockOptTimeval(sockfd, level, optname int32, tv *unix.Timeval) {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, err := dut.SetSockOptTimevalWithErrno(ctx, sockfd, level, optname, tv)
 	if ret != 0 {
@@ -580,15 +1008,50 @@ func (dut *DUT) Socket(domain, typ, proto int32) int32 {
 	return fd
 }
 
-// SocketWithErrno calls socket on the DUT and returns the fd and errno.
+// SocketWithErrno calls socket on the DUT and returns the fd and errno. If
+// typ sets SOCK_NONBLOCK or SOCK_CLOEXEC and the DUT's socket(2) rejects
+// those bits with EINVAL, as some older kernels do, it falls back to a
+// plain socket(2) followed by fcntl calls that apply the same flags
+// individually, mirroring the fallback Go's sysSocket performs, so the same
+// testbench binary runs against older DUT kernels.
 func (dut *DUT) SocketWithErrno(domain, typ, proto int32) (int32, error) {
+	dut.t.Helper()
+	ctx := dut.ctx
+	fd, errno := dut.socketWithErrno(ctx, domain, typ, proto)
+	if fd >= 0 || errno != syscall.EINVAL || typ&(unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC) == 0 {
+		return fd, errno
+	}
+	fd, errno = dut.socketWithErrno(ctx, domain, typ&^(unix.SOCK_NONBLOCK|unix.SOCK_CLOEXEC), proto)
+	if fd < 0 {
+		return fd, errno
+	}
+	if typ&unix.SOCK_NONBLOCK != 0 {
+		flags, ferr := dut.FcntlWithErrno(ctx, fd, unix.F_GETFL, 0)
+		if flags < 0 {
+			dut.CloseWithErrno(ctx, fd)
+			return -1, ferr
+		}
+		if ret, ferr := dut.FcntlWithErrno(ctx, fd, unix.F_SETFL, flags|unix.O_NONBLOCK); ret < 0 {
+			dut.CloseWithErrno(ctx, fd)
+			return -1, ferr
+		}
+	}
+	if typ&unix.SOCK_CLOEXEC != 0 {
+		if ret, ferr := dut.FcntlWithErrno(ctx, fd, unix.F_SETFD, unix.FD_CLOEXEC); ret < 0 {
+			dut.CloseWithErrno(ctx, fd)
+			return -1, ferr
+		}
+	}
+	return fd, nil
+}
+
+func (dut *DUT) socketWithErrno(ctx context.Context, domain, typ, proto int32) (int32, error) {
 	dut.t.Helper()
 	req := pb.SocketRequest{
 		Domain:   domain,
 		Type:     typ,
 		Protocol: proto,
 	}
-	ctx := context.Background()
 	resp, err := dut.posixServer.Socket(ctx, &req)
 	if err != nil {
 		dut.t.Fatalf("failed to call Socket: %s", err)
@@ -596,12 +1059,56 @@ func (dut *DUT) SocketWithErrno(domain, typ, proto int32) (int32, error) {
 	return resp.GetFd(), syscall.Errno(resp.GetErrno_())
 }
 
+// Fcntl calls fcntl on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use FcntlWithErrno.
+func (dut *DUT) Fcntl(fd, cmd, arg int32) int32 {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	ret, err := dut.FcntlWithErrno(ctx, fd, cmd, arg)
+	if ret < 0 {
+		dut.t.Fatalf("failed to fcntl: %s", err)
+	}
+	return ret
+}
+
+// FcntlWithErrno calls fcntl on the DUT.
+func (dut *DUT) FcntlWithErrno(ctx context.Context, fd, cmd, arg int32) (int32, error) {
+	dut.t.Helper()
+	req := pb.FcntlRequest{
+		Fd:  fd,
+		Cmd: cmd,
+		Arg: arg,
+	}
+	resp, err := dut.posixServer.Fcntl(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call Fcntl: %s", err)
+	}
+	return resp.GetRet(), syscall.Errno(resp.GetErrno_())
+}
+
+// SetBlocking sets or clears O_NONBLOCK on fd via fcntl and causes a fatal
+// test failure if it doesn't succeed. This lets a test drive an
+// EAGAIN-based state machine, e.g. asserting recv returns EAGAIN
+// immediately, without waiting out rpcTimeout on a blocking call.
+func (dut *DUT) SetBlocking(fd int32, blocking bool) {
+	dut.t.Helper()
+	flags := dut.Fcntl(fd, unix.F_GETFL, 0)
+	if blocking {
+		flags &^= unix.O_NONBLOCK
+	} else {
+		flags |= unix.O_NONBLOCK
+	}
+	dut.Fcntl(fd, unix.F_SETFL, flags)
+}
+
 // Recv calls recv on the DUT and causes a fatal test failure if it doesn't
 // succeed. If more control over the timeout or error handling is needed, use
 // RecvWithErrno.
 func (dut *DUT) Recv(sockfd, len, flags int32) []byte {
 	dut.t.Helper()
-	ctx, cancel := context.WithTimeout(context.Background(), *rpcTimeout)
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
 	defer cancel()
 	ret, buf, err := dut.RecvWithErrno(ctx, sockfd, len, flags)
 	if ret == -1 {
@@ -624,3 +1131,100 @@ func (dut *DUT) RecvWithErrno(ctx context.Context, sockfd, len, flags int32) (in
 	}
 	return resp.GetRet(), resp.GetBuf(), syscall.Errno(resp.GetErrno_())
 }
+
+// RecvContext calls recv on the DUT using ctx directly, rather than a
+// context derived from the DUT's context with rpcTimeout, and causes a fatal test
+// failure if it doesn't succeed. See AcceptContext for why this is useful.
+func (dut *DUT) RecvContext(ctx context.Context, sockfd, len, flags int32) []byte {
+	dut.t.Helper()
+	ret, buf, err := dut.RecvWithErrno(ctx, sockfd, len, flags)
+	if ret == -1 {
+		dut.t.Fatalf("failed to recv: %s", err)
+	}
+	return buf
+}
+
+// RecvStreamResult is one element pulled off the channel RecvStream
+// returns: either the next chunk recv'd from the DUT, or the error (which
+// may be io.EOF) that ended the stream. Reporting Err to the caller rather
+// than calling t.Errorf from the feeder goroutine avoids touching t after
+// the test that started the stream has already returned.
+type RecvStreamResult struct {
+	Buf []byte
+	Err error
+}
+
+// RecvStream opens a single gRPC stream bound to sockfd and returns a
+// channel carrying each successive recv'd chunk, removing the per-syscall
+// RPC latency that repeated RecvWithErrno calls would otherwise add to a
+// bulk-transfer test. The feeder goroutine exits, closing the channel,
+// as soon as the stream ends or ctx is done, so it never blocks forever
+// on a caller that stops draining the channel.
+func (dut *DUT) RecvStream(ctx context.Context, sockfd, len, flags int32) <-chan RecvStreamResult {
+	dut.t.Helper()
+	stream, err := dut.posixServer.RecvStream(ctx, &pb.RecvStreamRequest{Sockfd: sockfd, Len: len, Flags: flags})
+	if err != nil {
+		dut.t.Fatalf("failed to open RecvStream: %s", err)
+	}
+	out := make(chan RecvStreamResult)
+	go func() {
+		defer close(out)
+		for {
+			resp, err := stream.Recv()
+			var res RecvStreamResult
+			switch {
+			case err != nil:
+				res.Err = err
+			case resp.GetRet() < 0:
+				res.Err = syscall.Errno(resp.GetErrno_())
+			default:
+				res.Buf = resp.GetBuf()
+			}
+			select {
+			case out <- res:
+			case <-ctx.Done():
+				return
+			}
+			if res.Err != nil {
+				return
+			}
+		}
+	}()
+	return out
+}
+
+// RecvMsg calls recvmsg on the DUT and causes a fatal test failure if it
+// doesn't succeed. If more control over the timeout or error handling is
+// needed, use RecvMsgWithErrno.
+func (dut *DUT) RecvMsg(sockfd int32, iovLens []int32, controlLen, flags int32) ([][]byte, []ControlMessage, int32, unix.Sockaddr) {
+	dut.t.Helper()
+	ctx, cancel := context.WithTimeout(dut.ctx, *rpcTimeout)
+	defer cancel()
+	ret, iov, control, msgFlags, from, err := dut.RecvMsgWithErrno(ctx, sockfd, iovLens, controlLen, flags)
+	if ret == -1 {
+		dut.t.Fatalf("failed to recvmsg: %s", err)
+	}
+	return iov, control, msgFlags, from
+}
+
+// RecvMsgWithErrno calls recvmsg on the DUT. iovLens gives the size of each
+// iovec to read into and controlLen bounds the ancillary data buffer,
+// mirroring how the caller sizes msg_iov and msg_control before the syscall.
+func (dut *DUT) RecvMsgWithErrno(ctx context.Context, sockfd int32, iovLens []int32, controlLen, flags int32) (int32, [][]byte, []ControlMessage, int32, unix.Sockaddr, error) {
+	dut.t.Helper()
+	req := pb.RecvMsgRequest{
+		Sockfd:     sockfd,
+		IovLens:    iovLens,
+		ControlLen: controlLen,
+		Flags:      flags,
+	}
+	resp, err := dut.posixServer.RecvMsg(ctx, &req)
+	if err != nil {
+		dut.t.Fatalf("failed to call RecvMsg: %s", err)
+	}
+	var from unix.Sockaddr
+	if resp.GetFrom() != nil {
+		from = dut.protoToSockaddr(resp.GetFrom())
+	}
+	return resp.GetRet(), resp.GetIov(), controlMessagesFromProto(resp.GetControl()), resp.GetMsgFlags(), from, syscall.Errno(resp.GetErrno_())
+}
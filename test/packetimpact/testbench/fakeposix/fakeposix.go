@@ -0,0 +1,485 @@
+// Copyright 2020 The gVisor Authors.
+//
+// Licensed under the Apache License, Version 2.0 (the "License");
+// you may not use this file except in compliance with the License.
+// You may obtain a copy of the License at
+//
+//     http://www.apache.org/licenses/LICENSE-2.0
+//
+// Unless required by applicable law or agreed to in writing, software
+// distributed under the License is distributed on an "AS IS" BASIS,
+// WITHOUT WARRANTIES OR CONDITIONS OF ANY KIND, either express or implied.
+// See the License for the specific language governing permissions and
+// limitations under the License.
+
+// Package fakeposix implements testbench.PosixClient against an in-process
+// tcpip.Stack instead of a real posix_server reached over gRPC. It mirrors
+// the approach Go's net package takes for its wasip1/js fake syscalls: test
+// authors can exercise the testbench and tcpip stack bugs without standing
+// up the Docker two-netns rig, and CI can run the testbench's own unit
+// tests unprivileged.
+//
+// The fake covers the socket lifecycle (Socket, Bind, Listen, Accept,
+// Connect, Close, GetSockName, Send, SendTo, Recv) faithfully, translating
+// tcpip errors to the same Linux-shaped syscall.Errno values the real DUT
+// would return. Calls without an in-process equivalent, such as the raw
+// AF_PACKET paths, return ENOSYS.
+package fakeposix
+
+import (
+	"bytes"
+	"context"
+	"sync"
+	"syscall"
+
+	pb "gvisor.dev/gvisor/test/packetimpact/proto/posix_server_go_proto"
+
+	"golang.org/x/sys/unix"
+	"google.golang.org/grpc"
+
+	"gvisor.dev/gvisor/pkg/tcpip"
+	"gvisor.dev/gvisor/pkg/tcpip/header"
+	"gvisor.dev/gvisor/pkg/tcpip/link/loopback"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv4"
+	"gvisor.dev/gvisor/pkg/tcpip/network/ipv6"
+	"gvisor.dev/gvisor/pkg/tcpip/stack"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/tcp"
+	"gvisor.dev/gvisor/pkg/tcpip/transport/udp"
+	"gvisor.dev/gvisor/pkg/tcpip/waiter"
+)
+
+const nicID tcpip.NICID = 1
+
+// socket is the state the fake keeps for one file descriptor.
+type socket struct {
+	domain, typ, proto int32
+	ep                 tcpip.Endpoint
+	wq                 *waiter.Queue
+}
+
+// Client is an in-process stand-in for a posix_server, implementing
+// testbench.PosixClient against a private loopback-only tcpip.Stack. Use
+// NewClient to construct one, then pass it to testbench.NewDUTWithClient.
+type Client struct {
+	mu      sync.Mutex
+	stack   *stack.Stack
+	sockets map[int32]*socket
+	nextFD  int32
+}
+
+var _ pb.PosixClient = (*Client)(nil)
+
+// NewClient creates a Client backed by a fresh loopback-only network stack,
+// with 127.0.0.1/32 and ::1/128 already assigned to the loopback NIC.
+func NewClient() (*Client, error) {
+	s := stack.New(stack.Options{
+		NetworkProtocols:   []stack.NetworkProtocolFactory{ipv4.NewProtocol, ipv6.NewProtocol},
+		TransportProtocols: []stack.TransportProtocolFactory{tcp.NewProtocol, udp.NewProtocol},
+	})
+	if err := s.CreateNIC(nicID, loopback.New()); err != nil {
+		return nil, errnoFromTcpIPError(err)
+	}
+	for _, addr := range []tcpip.ProtocolAddress{
+		{Protocol: ipv4.ProtocolNumber, AddressWithPrefix: tcpip.Address("\x7f\x00\x00\x01").WithPrefix()},
+		{Protocol: ipv6.ProtocolNumber, AddressWithPrefix: tcpip.Address("\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x00\x01").WithPrefix()},
+	} {
+		if err := s.AddProtocolAddress(nicID, addr, stack.AddressProperties{}); err != nil {
+			return nil, errnoFromTcpIPError(err)
+		}
+	}
+	s.SetRouteTable([]tcpip.Route{
+		{Destination: header.IPv4EmptySubnet, NIC: nicID},
+		{Destination: header.IPv6EmptySubnet, NIC: nicID},
+	})
+	return &Client{
+		stack:   s,
+		sockets: make(map[int32]*socket),
+		nextFD:  1,
+	}, nil
+}
+
+func errnoFromTcpIPError(err tcpip.Error) error {
+	if err == nil {
+		return nil
+	}
+	switch err.(type) {
+	case *tcpip.ErrWouldBlock:
+		return syscall.EAGAIN
+	case *tcpip.ErrConnectionRefused:
+		return syscall.ECONNREFUSED
+	case *tcpip.ErrConnectionReset:
+		return syscall.ECONNRESET
+	case *tcpip.ErrConnectionAborted:
+		return syscall.ECONNABORTED
+	case *tcpip.ErrTimeout:
+		return syscall.ETIMEDOUT
+	case *tcpip.ErrAddressFamilyNotSupported:
+		return syscall.EAFNOSUPPORT
+	case *tcpip.ErrAlreadyConnecting:
+		return syscall.EALREADY
+	case *tcpip.ErrAlreadyConnected:
+		return syscall.EISCONN
+	case *tcpip.ErrInvalidEndpointState:
+		return syscall.EINVAL
+	case *tcpip.ErrNotConnected:
+		return syscall.ENOTCONN
+	case *tcpip.ErrPortInUse, *tcpip.ErrDuplicateAddress:
+		return syscall.EADDRINUSE
+	case *tcpip.ErrBadLocalAddress:
+		return syscall.EADDRNOTAVAIL
+	case *tcpip.ErrNoRoute:
+		return syscall.ENETUNREACH
+	case *tcpip.ErrMessageTooLong:
+		return syscall.EMSGSIZE
+	case *tcpip.ErrClosedForSend, *tcpip.ErrClosedForReceive:
+		return syscall.EPIPE
+	case *tcpip.ErrInvalidOptionValue:
+		return syscall.EINVAL
+	default:
+		return syscall.EINVAL
+	}
+}
+
+func errnoVal(err error) int32 {
+	if errno, ok := err.(syscall.Errno); ok {
+		return int32(errno)
+	}
+	return 0
+}
+
+func (c *Client) newFD(s *socket) int32 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	fd := c.nextFD
+	c.nextFD++
+	c.sockets[fd] = s
+	return fd
+}
+
+func (c *Client) socketByFD(fd int32) (*socket, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	s, ok := c.sockets[fd]
+	return s, ok
+}
+
+func networkProtocolNumber(domain int32) tcpip.NetworkProtocolNumber {
+	if domain == unix.AF_INET6 {
+		return ipv6.ProtocolNumber
+	}
+	return ipv4.ProtocolNumber
+}
+
+// Socket implements pb.PosixClient.
+func (c *Client) Socket(ctx context.Context, req *pb.SocketRequest, opts ...grpc.CallOption) (*pb.SocketResponse, error) {
+	switch req.GetDomain() {
+	case unix.AF_INET, unix.AF_INET6:
+	default:
+		return &pb.SocketResponse{Fd: -1, Errno_: errnoVal(notImplemented())}, nil
+	}
+	var transport tcpip.TransportProtocolNumber
+	switch req.GetType() &^ (unix.SOCK_NONBLOCK | unix.SOCK_CLOEXEC) {
+	case unix.SOCK_STREAM:
+		transport = tcp.ProtocolNumber
+	case unix.SOCK_DGRAM:
+		transport = udp.ProtocolNumber
+	default:
+		return &pb.SocketResponse{Fd: -1, Errno_: int32(syscall.EPROTONOSUPPORT)}, nil
+	}
+	var wq waiter.Queue
+	ep, err := c.stack.NewEndpoint(transport, networkProtocolNumber(req.GetDomain()), &wq)
+	if err != nil {
+		return &pb.SocketResponse{Fd: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+	}
+	fd := c.newFD(&socket{
+		domain: req.GetDomain(),
+		typ:    req.GetType(),
+		proto:  req.GetProtocol(),
+		ep:     ep,
+		wq:     &wq,
+	})
+	return &pb.SocketResponse{Fd: fd}, nil
+}
+
+func fullAddrFromProto(sa *pb.Sockaddr) tcpip.FullAddress {
+	switch s := sa.GetSockaddr().(type) {
+	case *pb.Sockaddr_In:
+		return tcpip.FullAddress{Addr: tcpip.Address(s.In.GetAddr()), Port: uint16(s.In.GetPort())}
+	case *pb.Sockaddr_In6:
+		return tcpip.FullAddress{Addr: tcpip.Address(s.In6.GetAddr()), Port: uint16(s.In6.GetPort())}
+	}
+	return tcpip.FullAddress{}
+}
+
+func protoFromFullAddr(domain int32, addr tcpip.FullAddress) *pb.Sockaddr {
+	if domain == unix.AF_INET6 {
+		return &pb.Sockaddr{Sockaddr: &pb.Sockaddr_In6{In6: &pb.SockaddrIn6{
+			Family: unix.AF_INET6,
+			Port:   uint32(addr.Port),
+			Addr:   []byte(addr.Addr),
+		}}}
+	}
+	return &pb.Sockaddr{Sockaddr: &pb.Sockaddr_In{In: &pb.SockaddrIn{
+		Family: unix.AF_INET,
+		Port:   uint32(addr.Port),
+		Addr:   []byte(addr.Addr),
+	}}}
+}
+
+// Bind implements pb.PosixClient.
+func (c *Client) Bind(ctx context.Context, req *pb.BindRequest, opts ...grpc.CallOption) (*pb.BindResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.BindResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	if err := s.ep.Bind(fullAddrFromProto(req.GetAddr())); err != nil {
+		return &pb.BindResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+	}
+	return &pb.BindResponse{Ret: 0}, nil
+}
+
+// Listen implements pb.PosixClient.
+func (c *Client) Listen(ctx context.Context, req *pb.ListenRequest, opts ...grpc.CallOption) (*pb.ListenResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.ListenResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	if err := s.ep.Listen(int(req.GetBacklog())); err != nil {
+		return &pb.ListenResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+	}
+	return &pb.ListenResponse{Ret: 0}, nil
+}
+
+// Accept implements pb.PosixClient.
+func (c *Client) Accept(ctx context.Context, req *pb.AcceptRequest, opts ...grpc.CallOption) (*pb.AcceptResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.AcceptResponse{Fd: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	var we waiter.Entry
+	ch := make(chan struct{}, 1)
+	we.Callback = waiter.CallbackFunc(func(*waiter.Entry, waiter.EventMask) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	s.wq.EventRegister(&we)
+	defer s.wq.EventUnregister(&we)
+	for {
+		ep, wq, err := s.ep.Accept(nil)
+		if err == nil {
+			fd := c.newFD(&socket{domain: s.domain, typ: s.typ, proto: s.proto, ep: ep, wq: wq})
+			addr, _ := ep.GetRemoteAddress()
+			return &pb.AcceptResponse{Fd: fd, Addr: protoFromFullAddr(s.domain, addr)}, nil
+		}
+		if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+			return &pb.AcceptResponse{Fd: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return &pb.AcceptResponse{Fd: -1, Errno_: int32(syscall.EAGAIN)}, nil
+		}
+	}
+}
+
+// Connect implements pb.PosixClient.
+func (c *Client) Connect(ctx context.Context, req *pb.ConnectRequest, opts ...grpc.CallOption) (*pb.ConnectResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.ConnectResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	if err := s.ep.Connect(fullAddrFromProto(req.GetAddr())); err != nil {
+		if _, ok := err.(*tcpip.ErrConnectStarted); !ok {
+			return &pb.ConnectResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+		}
+	}
+	return &pb.ConnectResponse{Ret: 0}, nil
+}
+
+// Close implements pb.PosixClient.
+func (c *Client) Close(ctx context.Context, req *pb.CloseRequest, opts ...grpc.CallOption) (*pb.CloseResponse, error) {
+	c.mu.Lock()
+	s, ok := c.sockets[req.GetFd()]
+	delete(c.sockets, req.GetFd())
+	c.mu.Unlock()
+	if !ok {
+		return &pb.CloseResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	s.ep.Close()
+	return &pb.CloseResponse{Ret: 0}, nil
+}
+
+// GetSockName implements pb.PosixClient.
+func (c *Client) GetSockName(ctx context.Context, req *pb.GetSockNameRequest, opts ...grpc.CallOption) (*pb.GetSockNameResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.GetSockNameResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	addr, err := s.ep.GetLocalAddress()
+	if err != nil {
+		return &pb.GetSockNameResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+	}
+	return &pb.GetSockNameResponse{Ret: 0, Addr: protoFromFullAddr(s.domain, addr)}, nil
+}
+
+// Send implements pb.PosixClient. It blocks until the endpoint is
+// writable, the ctx is done, or ep.Write fails for a reason other than
+// ErrWouldBlock, the same as Accept does for connections.
+func (c *Client) Send(ctx context.Context, req *pb.SendRequest, opts ...grpc.CallOption) (*pb.SendResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.SendResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	var we waiter.Entry
+	ch := make(chan struct{}, 1)
+	we.Callback = waiter.CallbackFunc(func(*waiter.Entry, waiter.EventMask) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	s.wq.EventRegister(&we)
+	defer s.wq.EventUnregister(&we)
+	for {
+		n, err := s.ep.Write(bytes.NewReader(req.GetBuf()), tcpip.WriteOptions{})
+		if err == nil {
+			return &pb.SendResponse{Ret: int32(n)}, nil
+		}
+		if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+			return &pb.SendResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return &pb.SendResponse{Ret: -1, Errno_: int32(syscall.EAGAIN)}, nil
+		}
+	}
+}
+
+// SendTo implements pb.PosixClient.
+func (c *Client) SendTo(ctx context.Context, req *pb.SendToRequest, opts ...grpc.CallOption) (*pb.SendToResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.SendToResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	to := fullAddrFromProto(req.GetDestAddr())
+	n, err := s.ep.Write(bytes.NewReader(req.GetBuf()), tcpip.WriteOptions{To: &to})
+	if err != nil {
+		return &pb.SendToResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+	}
+	return &pb.SendToResponse{Ret: int32(n)}, nil
+}
+
+// Recv implements pb.PosixClient. It caps how much ep.Read dequeues from
+// the endpoint to req.GetLen(), the same bound a real recv(2) buffer would
+// impose, so a short recv leaves the remainder queued for the next one
+// instead of pulling it out of the stack and discarding it here. It blocks
+// until data is readable, the ctx is done, or ep.Read fails for a reason
+// other than ErrWouldBlock, the same as Accept does for connections.
+func (c *Client) Recv(ctx context.Context, req *pb.RecvRequest, opts ...grpc.CallOption) (*pb.RecvResponse, error) {
+	s, ok := c.socketByFD(req.GetSockfd())
+	if !ok {
+		return &pb.RecvResponse{Ret: -1, Errno_: int32(syscall.EBADF)}, nil
+	}
+	var we waiter.Entry
+	ch := make(chan struct{}, 1)
+	we.Callback = waiter.CallbackFunc(func(*waiter.Entry, waiter.EventMask) {
+		select {
+		case ch <- struct{}{}:
+		default:
+		}
+	})
+	s.wq.EventRegister(&we)
+	defer s.wq.EventUnregister(&we)
+	for {
+		var buf bytes.Buffer
+		w := &tcpip.LimitedWriter{W: &buf, N: int64(req.GetLen())}
+		res, err := s.ep.Read(w, tcpip.ReadOptions{})
+		if err == nil {
+			return &pb.RecvResponse{Ret: int32(res.Count), Buf: buf.Bytes()}, nil
+		}
+		if _, ok := err.(*tcpip.ErrWouldBlock); !ok {
+			return &pb.RecvResponse{Ret: -1, Errno_: errnoVal(errnoFromTcpIPError(err))}, nil
+		}
+		select {
+		case <-ch:
+		case <-ctx.Done():
+			return &pb.RecvResponse{Ret: -1, Errno_: int32(syscall.EAGAIN)}, nil
+		}
+	}
+}
+
+// The remaining methods of pb.PosixClient have no in-process equivalent yet
+// and are left unimplemented; each reports ENOSYS the way a real DUT would
+// for an unsupported call.
+func notImplemented() error { return syscall.ENOSYS }
+
+func (c *Client) GetSockOpt(ctx context.Context, req *pb.GetSockOptRequest, opts ...grpc.CallOption) (*pb.GetSockOptResponse, error) {
+	return &pb.GetSockOptResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) GetSockOptInt(ctx context.Context, req *pb.GetSockOptIntRequest, opts ...grpc.CallOption) (*pb.GetSockOptIntResponse, error) {
+	return &pb.GetSockOptIntResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) GetSockOptTimeval(ctx context.Context, req *pb.GetSockOptTimevalRequest, opts ...grpc.CallOption) (*pb.GetSockOptTimevalResponse, error) {
+	return &pb.GetSockOptTimevalResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) SetSockOpt(ctx context.Context, req *pb.SetSockOptRequest, opts ...grpc.CallOption) (*pb.SetSockOptResponse, error) {
+	return &pb.SetSockOptResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) SetSockOptInt(ctx context.Context, req *pb.SetSockOptIntRequest, opts ...grpc.CallOption) (*pb.SetSockOptIntResponse, error) {
+	return &pb.SetSockOptIntResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) SetSockOptTimeval(ctx context.Context, req *pb.SetSockOptTimevalRequest, opts ...grpc.CallOption) (*pb.SetSockOptTimevalResponse, error) {
+	return &pb.SetSockOptTimevalResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) Poll(ctx context.Context, req *pb.PollRequest, opts ...grpc.CallOption) (*pb.PollResponse, error) {
+	return &pb.PollResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) Select(ctx context.Context, req *pb.SelectRequest, opts ...grpc.CallOption) (*pb.SelectResponse, error) {
+	return &pb.SelectResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) EpollCreate(ctx context.Context, req *pb.EpollCreateRequest, opts ...grpc.CallOption) (*pb.EpollCreateResponse, error) {
+	return &pb.EpollCreateResponse{Fd: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) EpollCtl(ctx context.Context, req *pb.EpollCtlRequest, opts ...grpc.CallOption) (*pb.EpollCtlResponse, error) {
+	return &pb.EpollCtlResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) EpollWait(ctx context.Context, req *pb.EpollWaitRequest, opts ...grpc.CallOption) (*pb.EpollWaitResponse, error) {
+	return &pb.EpollWaitResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) SendMsg(ctx context.Context, req *pb.SendMsgRequest, opts ...grpc.CallOption) (*pb.SendMsgResponse, error) {
+	return &pb.SendMsgResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) RecvMsg(ctx context.Context, req *pb.RecvMsgRequest, opts ...grpc.CallOption) (*pb.RecvMsgResponse, error) {
+	return &pb.RecvMsgResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}
+
+func (c *Client) SendStream(ctx context.Context, opts ...grpc.CallOption) (pb.Posix_SendStreamClient, error) {
+	return nil, notImplemented()
+}
+
+func (c *Client) RecvStream(ctx context.Context, req *pb.RecvStreamRequest, opts ...grpc.CallOption) (pb.Posix_RecvStreamClient, error) {
+	return nil, notImplemented()
+}
+
+func (c *Client) SendBatch(ctx context.Context, req *pb.SendBatchRequest, opts ...grpc.CallOption) (*pb.SendBatchResponse, error) {
+	return nil, notImplemented()
+}
+
+func (c *Client) Fcntl(ctx context.Context, req *pb.FcntlRequest, opts ...grpc.CallOption) (*pb.FcntlResponse, error) {
+	return &pb.FcntlResponse{Ret: -1, Errno_: errnoVal(notImplemented())}, nil
+}